@@ -0,0 +1,348 @@
+package radius
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// CoA-NAS default UDP port for RFC 5176 Change-of-Authorization and
+// Disconnect messages.
+const CoAPort = 3799
+
+// CoAHandler is implemented by code that wants to react to an incoming
+// Change-of-Authorization or Disconnect request. Session lookup (mapping the
+// request's attributes to a live NAS session) is left entirely to the
+// handler; CoAServer only takes care of parsing, Message-Authenticator
+// validation, and reply encoding.
+type CoAHandler interface {
+	ServeCoA(w ResponseWriter, r *Request)
+}
+
+// CoAHandlerFunc adapts an ordinary function to a CoAHandler.
+type CoAHandlerFunc func(w ResponseWriter, r *Request)
+
+// ServeCoA calls f(w, r).
+func (f CoAHandlerFunc) ServeCoA(w ResponseWriter, r *Request) {
+	f(w, r)
+}
+
+// CoAServer listens for and answers Disconnect-Request and CoA-Request
+// packets (RFC 5176). It shares the SecretSource and lifecycle conventions
+// of Server, but runs on its own UDP listener since CoA traffic normally
+// arrives on a different port than Access-Request/Accounting-Request
+// traffic.
+type CoAServer struct {
+	Addr         string       // UDP address to listen on, default ":3799"
+	Handler      CoAHandler   // handler to invoke for CoA/Disconnect requests
+	SecretSource SecretSource // secret source keyed on the NAS address
+
+	doneChan  chan struct{}
+	mu        sync.Mutex
+	waitGroup *sync.WaitGroup
+}
+
+// NewCoAServer returns a new CoAServer listening on addr (":3799" if empty)
+// that authenticates requests using secret and invokes handler.
+func NewCoAServer(addr string, secret []byte, handler CoAHandler) *CoAServer {
+	if addr == "" {
+		addr = net.JoinHostPort("", "3799")
+	}
+	return &CoAServer{
+		Addr:         addr,
+		Handler:      handler,
+		SecretSource: func(net.Addr) ([]byte, error) { return secret, nil },
+	}
+}
+
+// ListenAndServe listens on the UDP network address and answers
+// Disconnect-Request/CoA-Request packets until Shutdown is called.
+func (s *CoAServer) ListenAndServe() error {
+	if s.Handler == nil {
+		return errors.New("radius: nil CoAHandler")
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", s.Addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s.waitGroup = &sync.WaitGroup{}
+	for {
+		select {
+		case <-s.getDoneChan():
+			return nil
+		default:
+		}
+
+		conn.SetDeadline(time.Now().Add(2 * time.Second))
+		b := make([]byte, 4096)
+		n, remoteAddr, err := conn.ReadFrom(b)
+		if err != nil {
+			if opErr, ok := err.(*net.OpError); ok && opErr.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		s.waitGroup.Add(1)
+		go func(p []byte, remoteAddr net.Addr) {
+			defer s.waitGroup.Done()
+			s.handlePacket(conn, p, remoteAddr)
+		}(b[:n], remoteAddr)
+	}
+}
+
+func (s *CoAServer) handlePacket(conn net.PacketConn, p []byte, remoteAddr net.Addr) {
+	secret, err := s.SecretSource(remoteAddr)
+	if err != nil || len(secret) == 0 {
+		return
+	}
+
+	packet, err := Parse(p, secret)
+	if err != nil {
+		return
+	}
+
+	switch packet.Code {
+	case CodeDisconnectRequest, CodeCoARequest:
+	default:
+		// Not a code this server handles.
+		return
+	}
+
+	if err := validateMessageAuthenticator(p, secret); err != nil {
+		return
+	}
+
+	response := responseWriter{conn: conn, addr: remoteAddr}
+	request := Request{
+		LocalAddr:  conn.LocalAddr(),
+		RemoteAddr: remoteAddr,
+		Packet:     packet,
+	}
+
+	s.Handler.ServeCoA(&response, &request)
+}
+
+// radiusHeaderLen is the fixed Code(1)+Identifier(1)+Length(2)+
+// Authenticator(16) header every RADIUS packet starts with; attributes
+// follow as a sequence of Type(1)+Length(1)+Value TLVs.
+const radiusHeaderLen = 20
+
+// validateMessageAuthenticator verifies the Message-Authenticator attribute
+// required by RFC 5176 section 3 for all CoA/Disconnect requests and
+// responses: it is the HMAC-MD5 of the on-the-wire packet with the
+// Message-Authenticator value itself zeroed out. raw must be the exact
+// bytes received off the wire, since re-encoding the parsed *Packet would
+// recompute the Request Authenticator and no longer match what the sender
+// signed.
+func validateMessageAuthenticator(raw []byte, secret []byte) error {
+	if len(raw) < radiusHeaderLen {
+		return errors.New("radius: packet too short")
+	}
+
+	valueOffset, valueLen, err := findAttribute(raw, byte(TypeMessageAuthenticator))
+	if err != nil {
+		return err
+	}
+	if valueLen != md5.Size {
+		return errors.New("radius: invalid Message-Authenticator length")
+	}
+
+	want := make([]byte, valueLen)
+	copy(want, raw[valueOffset:valueOffset+valueLen])
+
+	zeroed := make([]byte, len(raw))
+	copy(zeroed, raw)
+	for i := 0; i < valueLen; i++ {
+		zeroed[valueOffset+i] = 0
+	}
+
+	mac := hmac.New(md5.New, secret)
+	mac.Write(zeroed)
+	sum := mac.Sum(nil)
+
+	if !hmac.Equal(sum, want) {
+		return errors.New("radius: Message-Authenticator mismatch")
+	}
+	return nil
+}
+
+// findAttribute walks the TLV attribute list of an on-the-wire RADIUS
+// packet looking for the first attribute of the given type, returning the
+// offset and length of its value (excluding the Type/Length bytes
+// themselves).
+func findAttribute(raw []byte, typ byte) (valueOffset, valueLen int, err error) {
+	offset := radiusHeaderLen
+	for offset < len(raw) {
+		if offset+2 > len(raw) {
+			return 0, 0, errors.New("radius: malformed attribute TLV")
+		}
+		attrType := raw[offset]
+		attrLen := int(raw[offset+1])
+		if attrLen < 2 || offset+attrLen > len(raw) {
+			return 0, 0, errors.New("radius: malformed attribute TLV")
+		}
+		if attrType == typ {
+			return offset + 2, attrLen - 2, nil
+		}
+		offset += attrLen
+	}
+	return 0, 0, errors.New("radius: missing Message-Authenticator")
+}
+
+// ErrorCause returns the numeric Error-Cause attribute value (RFC 5176
+// section 3.6) carried on a CoA/Disconnect NAK, or 0 and ErrNoAttribute if
+// the packet carries none.
+func ErrorCause(p *Packet) (uint32, error) {
+	attr, ok := p.Attributes.Lookup(TypeErrorCause)
+	if !ok {
+		return 0, ErrNoAttribute
+	}
+	return attr.Integer()
+}
+
+// NewErrorCause builds an Error-Cause attribute with one of the
+// ErrorCause* values below.
+func NewErrorCause(cause uint32) Attribute {
+	return NewInt(cause)
+}
+
+// Error-Cause values defined by RFC 5176 section 3.6.
+const (
+	ErrorCauseResidualSessionContextRemoved  uint32 = 201
+	ErrorCauseInvalidEAPPacket               uint32 = 202
+	ErrorCauseUnsupportedAttribute           uint32 = 401
+	ErrorCauseMissingAttribute               uint32 = 402
+	ErrorCauseNASIdentificationMismatch      uint32 = 403
+	ErrorCauseInvalidRequest                 uint32 = 404
+	ErrorCauseUnsupportedService             uint32 = 405
+	ErrorCauseUnsupportedExtension           uint32 = 406
+	ErrorCauseAdministrativelyProhibited     uint32 = 501
+	ErrorCauseRequestNotRoutable             uint32 = 502
+	ErrorCauseSessionContextNotFound         uint32 = 503
+	ErrorCauseSessionContextNotRemovable     uint32 = 504
+	ErrorCauseOtherProxyProcessingError      uint32 = 505
+	ErrorCauseResourcesUnavailable           uint32 = 506
+	ErrorCauseRequestInitiated               uint32 = 507
+	ErrorCauseMultipleSessionSelectionUnsupp uint32 = 508
+)
+
+// dynamicAuthTimeout is the per-attempt timeout sendDynamicAuth uses when
+// ctx carries no deadline of its own.
+const dynamicAuthTimeout = 5 * time.Second
+
+// dynamicAuthRetries is how many times sendDynamicAuth retransmits a
+// CoA/Disconnect request before giving up, per the NAS-side retransmit
+// behavior RFC 5176 assumes of its clients (RFC 2865 section 2.5).
+const dynamicAuthRetries = 3
+
+// SendCoA encodes and sends packet as a CoA-Request to addr, using
+// packet.Secret, then waits for the matching CoA-ACK/CoA-NAK reply or for
+// ctx to be done, whichever comes first, retransmitting if no reply
+// arrives within the per-attempt timeout.
+func SendCoA(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
+	packet.Code = CodeCoARequest
+	return sendDynamicAuth(ctx, packet, addr, CodeCoAACK, CodeCoANAK)
+}
+
+// SendDisconnect encodes and sends packet as a Disconnect-Request to addr,
+// using packet.Secret, then waits for the matching ACK/NAK reply or for
+// ctx to be done, whichever comes first, retransmitting if no reply
+// arrives within the per-attempt timeout.
+func SendDisconnect(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
+	packet.Code = CodeDisconnectRequest
+	return sendDynamicAuth(ctx, packet, addr, CodeDisconnectACK, CodeDisconnectNAK)
+}
+
+// sendDynamicAuth sends packet to addr and waits for a CoA-ACK/CoA-NAK or
+// Disconnect-ACK/Disconnect-NAK reply (whichever pair wantACK/wantNAK
+// names), retransmitting up to dynamicAuthRetries times if no reply
+// arrives within a per-attempt timeout (ctx's deadline, split across
+// attempts, or dynamicAuthTimeout if ctx carries none). The reply's
+// required Message-Authenticator (RFC 5176 section 3) is verified before
+// it is returned.
+func sendDynamicAuth(ctx context.Context, packet *Packet, addr string, wantACK, wantNAK Code) (*Packet, error) {
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	encoded, err := packet.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	attemptTimeout := dynamicAuthTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline) / (dynamicAuthRetries + 1); remaining < attemptTimeout {
+			attemptTimeout = remaining
+		}
+	}
+
+	type result struct {
+		raw  []byte
+		resp *Packet
+		err  error
+	}
+
+	for attempt := 0; attempt <= dynamicAuthRetries; attempt++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		if _, err := conn.Write(encoded); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(time.Now().Add(attemptTimeout))
+
+		done := make(chan result, 1)
+		go func() {
+			b := make([]byte, 4096)
+			n, err := conn.Read(b)
+			if err != nil {
+				done <- result{err: err}
+				return
+			}
+			resp, err := Parse(b[:n], packet.Secret)
+			done <- result{raw: append([]byte(nil), b[:n]...), resp: resp, err: err}
+		}()
+
+		select {
+		case <-ctx.Done():
+			conn.Close() // unblock the pending Read
+			<-done
+			return nil, ctx.Err()
+		case r := <-done:
+			if r.err != nil {
+				continue
+			}
+			if r.resp.Code != wantACK && r.resp.Code != wantNAK {
+				return nil, fmt.Errorf("radius: unexpected reply code %v", r.resp.Code)
+			}
+			if err := validateMessageAuthenticator(r.raw, packet.Secret); err != nil {
+				return nil, fmt.Errorf("radius: reply failed Message-Authenticator validation: %w", err)
+			}
+			return r.resp, nil
+		}
+	}
+	return nil, fmt.Errorf("radius: %s did not reply after %d retries", addr, dynamicAuthRetries)
+}
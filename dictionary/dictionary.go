@@ -0,0 +1,251 @@
+// Package dictionary turns the raw byte-slice radius.Attribute API into a
+// named one, comparable to layeh.com/radius: it parses FreeRADIUS-format
+// dictionary files, registers (vendor, type, name, kind) tuples, and
+// exposes Get/Add accessors that transparently pack and unpack
+// vendor-specific attributes (type 26), including salt, tag, and concat
+// (RFC 7268) encodings. The low-level radius.Attribute type is unchanged;
+// Dictionary is a layer on top of it.
+package dictionary
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ubogdan/radius"
+)
+
+// Kind is the wire encoding of a dictionary-registered attribute, as
+// declared by a FreeRADIUS "ATTRIBUTE" line.
+type Kind string
+
+// Kinds supported by Parse. Unrecognized FreeRADIUS types fall back to
+// KindOctets.
+const (
+	KindString   Kind = "string"
+	KindOctets   Kind = "octets"
+	KindInteger  Kind = "integer"
+	KindIPAddr   Kind = "ipaddr"
+	KindIPv6Addr Kind = "ipv6addr"
+	KindDate     Kind = "date"
+)
+
+// Flags carries the FreeRADIUS "has_tag"/"encrypt=N" attribute qualifiers
+// that change how a value is packed on the wire.
+type Flags struct {
+	HasTag  bool
+	Encrypt int // 0 = none, 1 = User-Password style unsalted MD5 XOR (RFC 2865 5.2), 2 = Tunnel-Password style salted (RFC 2868 3.5)
+}
+
+// Entry is a single named attribute registered in a Dictionary, uniquely
+// identified by (Vendor, Type).
+type Entry struct {
+	Vendor uint32 // 0 for non-vendor-specific attributes
+	Type   byte
+	Name   string
+	Kind   Kind
+	Flags  Flags
+}
+
+// Dictionary maps between RADIUS wire identifiers ((vendor, type) pairs)
+// and the human-readable attribute names used by Get/Add.
+type Dictionary struct {
+	byName  map[string]*Entry
+	byCode  map[codeKey]*Entry
+	vendors map[string]uint32 // vendor name -> vendor id, from VENDOR lines
+}
+
+type codeKey struct {
+	vendor uint32
+	typ    byte
+}
+
+// New returns an empty Dictionary.
+func New() *Dictionary {
+	return &Dictionary{
+		byName:  make(map[string]*Entry),
+		byCode:  make(map[codeKey]*Entry),
+		vendors: make(map[string]uint32),
+	}
+}
+
+// Register adds e to the dictionary, replacing any existing entry with
+// the same name or (vendor, type) pair.
+func (d *Dictionary) Register(e *Entry) {
+	d.byName[e.Name] = e
+	d.byCode[codeKey{e.Vendor, e.Type}] = e
+}
+
+// Lookup returns the Entry registered for name.
+func (d *Dictionary) Lookup(name string) (*Entry, bool) {
+	e, ok := d.byName[name]
+	return e, ok
+}
+
+// LookupCode returns the Entry registered for a (vendor, type) pair, 0
+// being the vendor for top-level RADIUS attributes.
+func (d *Dictionary) LookupCode(vendor uint32, typ byte) (*Entry, bool) {
+	e, ok := d.byCode[codeKey{vendor, typ}]
+	return e, ok
+}
+
+// Merge registers every entry of other into d, letting other's entries
+// take precedence on conflicts. It is used to layer a vendor bundle (e.g.
+// Cisco) on top of the base dictionary.
+func (d *Dictionary) Merge(other *Dictionary) {
+	for _, e := range other.byName {
+		d.Register(e)
+	}
+	for name, id := range other.vendors {
+		d.vendors[name] = id
+	}
+}
+
+// Parse reads a FreeRADIUS-format dictionary file from r and registers
+// its ATTRIBUTE and VENDOR declarations. VALUE lines and unsupported
+// directives are ignored, matching the subset of the format this module
+// needs to pack and unpack attributes (as opposed to FreeRADIUS's own use
+// of VALUE lines for display purposes).
+//
+//	ATTRIBUTE User-Name 1 string
+//	VENDOR Mikrotik 14988
+//	BEGIN-VENDOR Mikrotik
+//	ATTRIBUTE Mikrotik-Rate-Limit 8 string
+//	END-VENDOR Mikrotik
+func Parse(r io.Reader) (*Dictionary, error) {
+	d := New()
+	scanner := bufio.NewScanner(r)
+	var currentVendor uint32
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+
+		switch strings.ToUpper(fields[0]) {
+		case "VENDOR":
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("dictionary: line %d: malformed VENDOR", lineNo)
+			}
+			id, err := strconv.ParseUint(fields[2], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("dictionary: line %d: %w", lineNo, err)
+			}
+			d.vendors[fields[1]] = uint32(id)
+
+		case "BEGIN-VENDOR":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("dictionary: line %d: malformed BEGIN-VENDOR", lineNo)
+			}
+			id, ok := d.vendors[fields[1]]
+			if !ok {
+				return nil, fmt.Errorf("dictionary: line %d: unknown vendor %q", lineNo, fields[1])
+			}
+			currentVendor = id
+
+		case "END-VENDOR":
+			currentVendor = 0
+
+		case "ATTRIBUTE":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("dictionary: line %d: malformed ATTRIBUTE", lineNo)
+			}
+			typ, err := strconv.ParseUint(fields[2], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("dictionary: line %d: %w", lineNo, err)
+			}
+			e := &Entry{
+				Vendor: currentVendor,
+				Type:   byte(typ),
+				Name:   fields[1],
+				Kind:   parseKind(fields[3]),
+			}
+			for _, qualifier := range fields[4:] {
+				applyQualifier(&e.Flags, qualifier)
+			}
+			d.Register(e)
+
+		default:
+			// VALUE and other FreeRADIUS directives aren't needed to
+			// pack/unpack attributes; skip them.
+		}
+	}
+	return d, scanner.Err()
+}
+
+func parseKind(s string) Kind {
+	switch Kind(strings.ToLower(s)) {
+	case KindString, KindOctets, KindInteger, KindIPAddr, KindIPv6Addr, KindDate:
+		return Kind(strings.ToLower(s))
+	default:
+		return KindOctets
+	}
+}
+
+// applyQualifier applies one whitespace-delimited ATTRIBUTE qualifier
+// field to f. FreeRADIUS allows several comma-joined flags in a single
+// field (e.g. "has_tag,encrypt=2"), so each comma-separated part is
+// applied independently.
+func applyQualifier(f *Flags, qualifier string) {
+	for _, part := range strings.Split(qualifier, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "has_tag":
+			f.HasTag = true
+		case strings.HasPrefix(part, "encrypt="):
+			n, err := strconv.Atoi(strings.TrimPrefix(part, "encrypt="))
+			if err == nil {
+				f.Encrypt = n
+			}
+		}
+	}
+}
+
+// Get looks up name in d and returns its decoded value from p: a string
+// for KindString, a uint32 for KindInteger, a net.IP for KindIPAddr/
+// KindIPv6Addr, a time.Time for KindDate, or a []byte for KindOctets.
+// ErrUnknownAttribute is returned if name isn't registered;
+// radius.ErrNoAttribute if it is registered but absent from p.
+func (d *Dictionary) Get(p *radius.Packet, name string) (interface{}, error) {
+	e, ok := d.byName[name]
+	if !ok {
+		return nil, ErrUnknownAttribute
+	}
+
+	var raw radius.Attribute
+	var err error
+	if e.Vendor == 0 {
+		raw, err = lookupTopLevel(p, e.Type)
+	} else {
+		raw, err = lookupVSA(p, e.Vendor, e.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(p, e, raw)
+}
+
+// Add packs value according to name's registered Kind and appends it to
+// p as a top-level attribute (Vendor == 0) or a type-26 VSA.
+func (d *Dictionary) Add(p *radius.Packet, name string, value interface{}) error {
+	e, ok := d.byName[name]
+	if !ok {
+		return ErrUnknownAttribute
+	}
+
+	raw, err := encode(p, e, value)
+	if err != nil {
+		return err
+	}
+
+	if e.Vendor == 0 {
+		p.Attributes.Add(radius.Type(e.Type), raw)
+		return nil
+	}
+	return addVSA(p, e.Vendor, e.Type, raw)
+}
@@ -0,0 +1,139 @@
+package dictionary
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ubogdan/radius"
+)
+
+func TestApplyQualifierCombinedToken(t *testing.T) {
+	var f Flags
+	applyQualifier(&f, "has_tag,encrypt=2")
+
+	if !f.HasTag {
+		t.Error("expected HasTag to be set from a comma-joined qualifier")
+	}
+	if f.Encrypt != 2 {
+		t.Errorf("expected Encrypt=2, got %d", f.Encrypt)
+	}
+}
+
+func TestApplyQualifierSeparateTokens(t *testing.T) {
+	var f Flags
+	applyQualifier(&f, "has_tag")
+	applyQualifier(&f, "encrypt=1")
+
+	if !f.HasTag || f.Encrypt != 1 {
+		t.Errorf("got HasTag=%v Encrypt=%d, want HasTag=true Encrypt=1", f.HasTag, f.Encrypt)
+	}
+}
+
+func TestSaltEncryptDecryptRoundTrip(t *testing.T) {
+	secret := []byte("sharedsecret")
+	requestAuthenticator := bytes.Repeat([]byte{0x42}, 16)
+	plaintext := []byte("hunter2")
+
+	ciphertext, err := SaltEncrypt(secret, requestAuthenticator, plaintext)
+	if err != nil {
+		t.Fatalf("SaltEncrypt: %v", err)
+	}
+
+	got, err := SaltDecrypt(secret, requestAuthenticator, ciphertext)
+	if err != nil {
+		t.Fatalf("SaltDecrypt: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("got %q, want %q", got, plaintext)
+	}
+}
+
+func TestSaltEncryptDecryptRoundTripPreservesTrailingZeroBytes(t *testing.T) {
+	secret := []byte("sharedsecret")
+	requestAuthenticator := bytes.Repeat([]byte{0x42}, 16)
+	// A plaintext ending in 0x00 must not be mistaken for zero padding;
+	// the Data-Length octet is what disambiguates it.
+	plaintext := []byte("hunter2\x00\x00")
+
+	ciphertext, err := SaltEncrypt(secret, requestAuthenticator, plaintext)
+	if err != nil {
+		t.Fatalf("SaltEncrypt: %v", err)
+	}
+
+	got, err := SaltDecrypt(secret, requestAuthenticator, ciphertext)
+	if err != nil {
+		t.Fatalf("SaltDecrypt: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("got %q, want %q", got, plaintext)
+	}
+}
+
+func TestUserPasswordEncryptDecryptRoundTrip(t *testing.T) {
+	secret := []byte("sharedsecret")
+	requestAuthenticator := bytes.Repeat([]byte{0x07}, 16)
+	plaintext := []byte("correct horse battery staple")
+
+	ciphertext := encryptUserPassword(secret, requestAuthenticator, plaintext)
+	got := decryptUserPassword(secret, requestAuthenticator, ciphertext)
+
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("got %q, want %q", got, plaintext)
+	}
+}
+
+func TestVSAConcatRoundTrip(t *testing.T) {
+	p := &radius.Packet{Attributes: make(radius.Attributes)}
+
+	// Larger than a single Vendor-Data field (253 - 6 = 247 bytes), to
+	// force the RFC 7268 concat path across multiple VSAs.
+	value := bytes.Repeat([]byte{0xAB}, 500)
+
+	if err := addVSA(p, 14988, 8, value); err != nil {
+		t.Fatalf("addVSA: %v", err)
+	}
+
+	vsas := p.Attributes[radius.Type(vendorSpecificType)]
+	if len(vsas) < 2 {
+		t.Fatalf("expected value to be split across multiple VSAs, got %d", len(vsas))
+	}
+
+	got, err := lookupVSA(p, 14988, 8)
+	if err != nil {
+		t.Fatalf("lookupVSA: %v", err)
+	}
+	if !bytes.Equal([]byte(got), value) {
+		t.Fatalf("reassembled VSA value does not match original")
+	}
+}
+
+func TestEncodeDecryptEncryptedAttribute(t *testing.T) {
+	d := New()
+	d.Register(&Entry{Vendor: 0, Type: 2, Name: "User-Password", Kind: KindString, Flags: Flags{Encrypt: 1}})
+
+	p := &radius.Packet{
+		Secret:     []byte("sharedsecret"),
+		Attributes: make(radius.Attributes),
+	}
+	copy(p.Authenticator[:], bytes.Repeat([]byte{0x11}, 16))
+
+	if err := d.Add(p, "User-Password", "hunter2"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	raw, ok := p.Attributes.Lookup(radius.Type(2))
+	if !ok {
+		t.Fatal("expected User-Password attribute to be added")
+	}
+	if bytes.Contains([]byte(raw), []byte("hunter2")) {
+		t.Fatal("encrypted attribute should not contain the plaintext password on the wire")
+	}
+
+	got, err := d.Get(p, "User-Password")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got != "hunter2" {
+		t.Fatalf("got %q, want %q", got, "hunter2")
+	}
+}
@@ -0,0 +1,30 @@
+package dictionary
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+)
+
+//go:embed vendors/*.dictionary
+var vendorFS embed.FS
+
+// Vendor bundle names accepted by LoadVendor.
+const (
+	VendorCisco      = "cisco"
+	VendorMikrotik   = "mikrotik"
+	VendorWISPr      = "wispr"
+	VendorChilliSpot = "chillispot"
+)
+
+// LoadVendor parses the embedded FreeRADIUS-format dictionary bundled for
+// name (one of the Vendor* constants) without requiring the caller to
+// ship dictionary files alongside the binary.
+func LoadVendor(name string) (*Dictionary, error) {
+	data, err := vendorFS.Open("vendors/" + strings.ToLower(name) + ".dictionary")
+	if err != nil {
+		return nil, fmt.Errorf("dictionary: unknown vendor bundle %q: %w", name, err)
+	}
+	defer data.Close()
+	return Parse(data)
+}
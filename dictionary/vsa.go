@@ -0,0 +1,319 @@
+package dictionary
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/ubogdan/radius"
+)
+
+// ErrUnknownAttribute is returned by Get/Add when the requested name has
+// no Entry registered in the Dictionary.
+var ErrUnknownAttribute = errors.New("dictionary: unknown attribute")
+
+// vendorSpecificType is the standard RADIUS attribute type (26) used to
+// carry vendor-specific attributes, per RFC 2865 section 5.26.
+const vendorSpecificType = 26
+
+func lookupTopLevel(p *radius.Packet, typ byte) (radius.Attribute, error) {
+	attr, ok := p.Attributes.Lookup(radius.Type(typ))
+	if !ok {
+		return nil, radius.ErrNoAttribute
+	}
+	return attr, nil
+}
+
+// lookupVSA scans the Vendor-Specific attributes on p for one matching
+// (vendor, typ), decoding the RFC 2865 5.26 Vendor-Id/Vendor-Type/
+// Vendor-Length/Vendor-Data envelope and, where the value was split
+// across multiple VSAs (RFC 7268 concat), reassembling it.
+func lookupVSA(p *radius.Packet, vendor uint32, typ byte) (radius.Attribute, error) {
+	var concatenated []byte
+	found := false
+
+	for _, raw := range p.Attributes[radius.Type(vendorSpecificType)] {
+		if len(raw) < 6 {
+			continue
+		}
+		vendorID := uint32(raw[0])<<24 | uint32(raw[1])<<16 | uint32(raw[2])<<8 | uint32(raw[3])
+		if vendorID != vendor {
+			continue
+		}
+		vendorType := raw[4]
+		vendorLen := int(raw[5])
+		if vendorType != typ || len(raw) < 6+vendorLen-2 {
+			continue
+		}
+		found = true
+		concatenated = append(concatenated, raw[6:]...)
+	}
+
+	if !found {
+		return nil, radius.ErrNoAttribute
+	}
+	return radius.Attribute(concatenated), nil
+}
+
+// addVSA appends value to p as one or more Vendor-Specific attributes,
+// splitting it across multiple VSAs (RFC 7268 concat) if it doesn't fit
+// in a single VSA's 247-byte Vendor-Data field (a 253-byte attribute
+// value less the 6-byte Vendor-Id/Vendor-Type/Vendor-Length header).
+func addVSA(p *radius.Packet, vendor uint32, typ byte, value []byte) error {
+	const maxVendorData = 253 - 6 // Vendor-Id(4) + Vendor-Type(1) + Vendor-Length(1)
+
+	for offset := 0; offset == 0 || offset < len(value); {
+		end := offset + maxVendorData
+		if end > len(value) {
+			end = len(value)
+		}
+		chunk := value[offset:end]
+
+		raw := make([]byte, 6+len(chunk))
+		raw[0] = byte(vendor >> 24)
+		raw[1] = byte(vendor >> 16)
+		raw[2] = byte(vendor >> 8)
+		raw[3] = byte(vendor)
+		raw[4] = typ
+		raw[5] = byte(2 + len(chunk))
+		copy(raw[6:], chunk)
+
+		p.Attributes.Add(radius.Type(vendorSpecificType), radius.Attribute(raw))
+		offset = end
+		if len(value) == 0 {
+			break
+		}
+	}
+	return nil
+}
+
+// decode unpacks raw according to e's registered Kind, first stripping a
+// leading tag byte (if e.Flags.HasTag) and then decrypting (if
+// e.Flags.Encrypt is set), so callers always get back the plaintext value
+// regardless of how it was protected on the wire.
+func decode(p *radius.Packet, e *Entry, raw radius.Attribute) (interface{}, error) {
+	payload := []byte(raw)
+	if e.Flags.HasTag && len(payload) > 0 {
+		payload = payload[1:]
+	}
+
+	var err error
+	switch e.Flags.Encrypt {
+	case 1:
+		payload = decryptUserPassword(p.Secret, p.Authenticator[:], payload)
+	case 2:
+		payload, err = SaltDecrypt(p.Secret, p.Authenticator[:], payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	attr := radius.Attribute(payload)
+	switch e.Kind {
+	case KindString:
+		return attr.String(), nil
+	case KindInteger:
+		return attr.Integer()
+	case KindIPAddr:
+		return attr.IPAddr()
+	case KindIPv6Addr:
+		return attr.IPv6Addr()
+	case KindDate:
+		return attr.Time()
+	default:
+		return attr.Bytes(), nil
+	}
+}
+
+// encode packs value according to e's registered Kind, then encrypts (if
+// e.Flags.Encrypt is set) and finally prepends a tag byte (if
+// e.Flags.HasTag), matching the on-the-wire order required by RFC 2868
+// section 3.5: Tag, then Salt+Ciphertext.
+func encode(p *radius.Packet, e *Entry, value interface{}) (radius.Attribute, error) {
+	raw, err := encodeValue(e.Kind, value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Flags.Encrypt {
+	case 1:
+		raw = radius.Attribute(encryptUserPassword(p.Secret, p.Authenticator[:], raw))
+	case 2:
+		enc, err := SaltEncrypt(p.Secret, p.Authenticator[:], raw)
+		if err != nil {
+			return nil, err
+		}
+		raw = radius.Attribute(enc)
+	}
+
+	if e.Flags.HasTag {
+		tagged := make(radius.Attribute, len(raw)+1)
+		tagged[0] = 0
+		copy(tagged[1:], raw)
+		raw = tagged
+	}
+
+	return raw, nil
+}
+
+func encodeValue(kind Kind, value interface{}) (radius.Attribute, error) {
+	switch kind {
+	case KindString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("dictionary: value must be a string")
+		}
+		return radius.NewString(s)
+	case KindInteger:
+		i, ok := value.(uint32)
+		if !ok {
+			return nil, errors.New("dictionary: value must be a uint32")
+		}
+		return radius.NewInt(i), nil
+	case KindIPAddr:
+		ip, ok := value.(net.IP)
+		if !ok {
+			return nil, errors.New("dictionary: value must be a net.IP")
+		}
+		return radius.NewIPAddr(ip)
+	case KindIPv6Addr:
+		ip, ok := value.(net.IP)
+		if !ok {
+			return nil, errors.New("dictionary: value must be a net.IP")
+		}
+		return radius.NewIPv6Addr(ip)
+	case KindDate:
+		t, ok := value.(time.Time)
+		if !ok {
+			return nil, errors.New("dictionary: value must be a time.Time")
+		}
+		return radius.NewTime(t)
+	default:
+		b, ok := value.([]byte)
+		if !ok {
+			return nil, errors.New("dictionary: value must be a []byte")
+		}
+		return radius.NewBytes(b)
+	}
+}
+
+// encryptUserPassword applies the RFC 2865 section 5.2 User-Password
+// encryption: plaintext is zero-padded to a multiple of 16 bytes and
+// XORed, 16 bytes at a time, against MD5(secret || prev), where prev is
+// the Request Authenticator for the first block and the previous
+// ciphertext block thereafter.
+func encryptUserPassword(secret, requestAuthenticator, plaintext []byte) []byte {
+	padded := plaintext
+	if r := len(padded) % 16; r != 0 {
+		padded = append(append([]byte{}, padded...), make([]byte, 16-r)...)
+	}
+
+	out := make([]byte, len(padded))
+	prev := requestAuthenticator
+	for i := 0; i < len(padded); i += 16 {
+		hash := md5.Sum(append(append([]byte{}, secret...), prev...))
+		for j := 0; j < 16; j++ {
+			out[i+j] = padded[i+j] ^ hash[j]
+		}
+		prev = out[i : i+16]
+	}
+	return out
+}
+
+// decryptUserPassword reverses encryptUserPassword, then trims the zero
+// padding the encoder appended.
+func decryptUserPassword(secret, requestAuthenticator, ciphertext []byte) []byte {
+	out := make([]byte, len(ciphertext))
+	prev := requestAuthenticator
+	for i := 0; i+16 <= len(ciphertext); i += 16 {
+		hash := md5.Sum(append(append([]byte{}, secret...), prev...))
+		block := ciphertext[i : i+16]
+		for j := 0; j < 16; j++ {
+			out[i+j] = block[j] ^ hash[j]
+		}
+		prev = block
+	}
+	return bytes.TrimRight(out, "\x00")
+}
+
+// SaltEncrypt applies the RFC 2868 section 3.5 "Tunnel-Password" salted
+// encryption to plaintext, using secret and the packet's Request
+// Authenticator. The returned bytes are Salt(2) followed by the
+// ciphertext, ready to follow a Tag byte for a tagged attribute.
+func SaltEncrypt(secret, requestAuthenticator, plaintext []byte) ([]byte, error) {
+	if len(plaintext) > 253 {
+		return nil, errors.New("dictionary: plaintext too long for a salted attribute")
+	}
+
+	salt := make([]byte, 2)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	salt[0] |= 1 << 7 // RFC 2868: the high bit of the salt must be set
+
+	// RFC 2868 3.5: the String field is a one-octet Data-Length followed
+	// by the password, the whole thing then zero-padded to a multiple of
+	// 16 bytes. Data-Length is what lets a decoder recover the exact
+	// plaintext length even when it legitimately ends in 0x00 bytes.
+	unpadded := make([]byte, 1+len(plaintext))
+	unpadded[0] = byte(len(plaintext))
+	copy(unpadded[1:], plaintext)
+
+	padded := unpadded
+	if r := len(padded) % 16; r != 0 {
+		padded = append(append([]byte{}, padded...), make([]byte, 16-r)...)
+	}
+
+	out := make([]byte, 0, 2+len(padded))
+	out = append(out, salt...)
+
+	prev := append(append([]byte{}, requestAuthenticator...), salt...)
+	for i := 0; i < len(padded); i += 16 {
+		hash := md5.Sum(append(append([]byte{}, secret...), prev...))
+		block := make([]byte, 16)
+		for j := 0; j < 16; j++ {
+			block[j] = padded[i+j] ^ hash[j]
+		}
+		out = append(out, block...)
+		prev = block
+	}
+	return out, nil
+}
+
+// SaltDecrypt reverses SaltEncrypt: ciphertext is Salt(2) followed by one
+// or more 16-byte blocks, each XORed against MD5(secret || prev), where
+// prev is Request-Authenticator||Salt for the first block and the
+// previous ciphertext block thereafter. The decrypted String's leading
+// Data-Length octet (RFC 2868 3.5) is used to slice off the exact
+// plaintext, rather than trimming zero padding, so a plaintext that
+// legitimately ends in 0x00 bytes round-trips correctly.
+func SaltDecrypt(secret, requestAuthenticator, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 2 || (len(ciphertext)-2)%16 != 0 {
+		return nil, errors.New("dictionary: invalid salted attribute length")
+	}
+	salt := ciphertext[:2]
+	data := ciphertext[2:]
+
+	out := make([]byte, len(data))
+	prev := append(append([]byte{}, requestAuthenticator...), salt...)
+	for i := 0; i < len(data); i += 16 {
+		hash := md5.Sum(append(append([]byte{}, secret...), prev...))
+		block := data[i : i+16]
+		for j := 0; j < 16; j++ {
+			out[i+j] = block[j] ^ hash[j]
+		}
+		prev = block
+	}
+
+	if len(out) < 1 {
+		return nil, errors.New("dictionary: salted attribute missing Data-Length octet")
+	}
+	dataLength := int(out[0])
+	if dataLength > len(out)-1 {
+		return nil, errors.New("dictionary: salted attribute Data-Length exceeds decrypted payload")
+	}
+	return out[1 : 1+dataLength], nil
+}
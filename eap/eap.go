@@ -0,0 +1,189 @@
+// Package eap implements the fragmentation, reassembly, and TLS handshake
+// state machine shared by EAP-TLS (RFC 5216), PEAPv0, and EAP-TTLS on top of
+// the single-frame radius.EapMessage type.
+package eap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ubogdan/radius"
+)
+
+// Flags are the L/M/S bits carried in the first byte of an EAP-TLS/PEAP/
+// TTLS type-data field, as defined by RFC 5216 section 3.1.
+type Flags byte
+
+const (
+	// FlagLengthIncluded indicates the 4-byte TLS Message Length field is
+	// present, immediately following the flags byte.
+	FlagLengthIncluded Flags = 1 << 7
+	// FlagMoreFragments indicates more fragments of this TLS message
+	// follow in subsequent Access-Challenge/Access-Request round trips.
+	FlagMoreFragments Flags = 1 << 6
+	// FlagStart marks the EAP-TLS/PEAP/TTLS start packet that begins a
+	// new handshake, carrying no TLS data.
+	FlagStart Flags = 1 << 5
+)
+
+// ErrTruncated is returned by Reassembler.Add when more fragments are
+// required before Bytes can be called.
+var ErrTruncated = errors.New("eap: message truncated, more fragments required")
+
+// MaxTypeDataSize is the largest type-data fragment that safely fits in a
+// single EAP-Message attribute once the EAP header (Code, Identifier,
+// Length, Type: 5 bytes) and the RADIUS attribute's own Type/Length bytes
+// are accounted for, leaving the 253-byte radius.Attribute payload limit
+// some headroom. Callers that don't have a narrower MTU constraint of
+// their own should pass this to NewFragmenter.
+const MaxTypeDataSize = 247
+
+// Fragmenter splits an outbound TLS record stream into chunks no larger
+// than maxFragmentSize, suitable for sending one per Access-Challenge as
+// required when a full handshake message doesn't fit in a single RADIUS
+// packet.
+type Fragmenter struct {
+	data        []byte
+	offset      int
+	maxFragment int
+}
+
+// NewFragmenter returns a Fragmenter that will emit type-data fields
+// (flags byte, optional length field, and payload) of at most
+// maxFragmentSize bytes. maxFragmentSize is clamped to MaxTypeDataSize if
+// it is not positive or is larger, and raised to 6 (the first fragment's
+// 5 header bytes plus one payload byte) if it is smaller than that.
+func NewFragmenter(data []byte, maxFragmentSize int) *Fragmenter {
+	if maxFragmentSize <= 0 || maxFragmentSize > MaxTypeDataSize {
+		maxFragmentSize = MaxTypeDataSize
+	}
+	if maxFragmentSize < 6 {
+		maxFragmentSize = 6
+	}
+	return &Fragmenter{data: data, maxFragment: maxFragmentSize}
+}
+
+// Next returns the next type-data field to send (flags byte, optional
+// length, and fragment payload) and whether more fragments remain after
+// it.
+func (f *Fragmenter) Next() (typeData []byte, more bool) {
+	remaining := f.data[f.offset:]
+	first := f.offset == 0
+
+	// maxFragment bounds the whole type-data field, so the flags byte
+	// (and, on the first fragment, the 4-byte length) come out of it.
+	limit := f.maxFragment - 1
+	if first {
+		limit -= 4
+	}
+
+	chunk := remaining
+	more = false
+	if len(chunk) > limit {
+		chunk = chunk[:limit]
+		more = true
+	}
+	f.offset += len(chunk)
+
+	var flags Flags
+	if more {
+		flags |= FlagMoreFragments
+	}
+
+	var out []byte
+	if first {
+		flags |= FlagLengthIncluded
+		out = make([]byte, 1+4+len(chunk))
+		out[0] = byte(flags)
+		binary.BigEndian.PutUint32(out[1:5], uint32(len(f.data)))
+		copy(out[5:], chunk)
+	} else {
+		out = make([]byte, 1+len(chunk))
+		out[0] = byte(flags)
+		copy(out[1:], chunk)
+	}
+	return out, more
+}
+
+// ErrLengthMismatch is returned by Add when the final fragment (the one
+// without FlagMoreFragments set) leaves the reassembly buffer short of
+// the length prefix carried in the first fragment. A peer that clears
+// FlagMoreFragments early would otherwise make a truncated message look
+// complete.
+var ErrLengthMismatch = errors.New("eap: reassembled message does not match the length prefix")
+
+// Reassembler buffers inbound EAP-TLS/PEAP/TTLS fragments until the
+// length prefix carried in the first fragment is satisfied.
+type Reassembler struct {
+	buf         []byte
+	wantLength  int
+	lengthKnown bool
+	started     bool
+}
+
+// Add appends the type-data field from one EAP-Message to the
+// reassembly buffer. It returns ErrTruncated if more fragments (flagged
+// with FlagMoreFragments) are still expected, or ErrLengthMismatch if the
+// peer claims reassembly is done before the length prefix is satisfied.
+func (r *Reassembler) Add(typeData []byte) error {
+	if len(typeData) < 1 {
+		return errors.New("eap: empty type-data field")
+	}
+	flags := Flags(typeData[0])
+	body := typeData[1:]
+
+	if !r.started {
+		r.started = true
+		if flags&FlagLengthIncluded != 0 {
+			if len(body) < 4 {
+				return errors.New("eap: truncated length field")
+			}
+			r.wantLength = int(binary.BigEndian.Uint32(body[:4]))
+			r.lengthKnown = true
+			body = body[4:]
+		}
+	}
+
+	r.buf = append(r.buf, body...)
+
+	if flags&FlagMoreFragments != 0 {
+		return ErrTruncated
+	}
+	if r.lengthKnown && len(r.buf) != r.wantLength {
+		return ErrLengthMismatch
+	}
+	return nil
+}
+
+// Bytes returns the fully reassembled message. It is only valid once Add
+// has returned nil (no more fragments pending).
+func (r *Reassembler) Bytes() []byte {
+	return r.buf
+}
+
+// Reset clears the reassembler so it can be reused for the next TLS
+// message in the same session.
+func (r *Reassembler) Reset() {
+	r.buf = nil
+	r.wantLength = 0
+	r.lengthKnown = false
+	r.started = false
+}
+
+// DecodeTypeData extracts the EAP-TLS/PEAP/TTLS type-data field (the
+// bytes following the EAP Type byte) from a radius.EapMessage.
+func DecodeTypeData(msg *radius.EapMessage) []byte {
+	return msg.Data.Bytes()
+}
+
+// EncodeTypeData wraps a type-data field (as produced by Fragmenter.Next,
+// and therefore no larger than MaxTypeDataSize) into an outbound
+// radius.EapMessage of the given eapType, identifier, and code.
+func EncodeTypeData(code radius.EapCode, identifier uint8, eapType radius.EapType, typeData []byte) (radius.Attribute, error) {
+	data, err := radius.NewBytes(typeData)
+	if err != nil {
+		return nil, fmt.Errorf("eap: encoding type-data: %w", err)
+	}
+	return radius.NewEAPMessage(code, identifier, eapType, data), nil
+}
@@ -0,0 +1,43 @@
+package eap
+
+import "testing"
+
+func TestReassemblerRejectsShortFinalFragment(t *testing.T) {
+	var r Reassembler
+
+	first := append([]byte{byte(FlagLengthIncluded | FlagMoreFragments), 0, 0, 0, 10}, []byte("hel")...)
+	if err := r.Add(first); err != ErrTruncated {
+		t.Fatalf("first fragment: got %v, want ErrTruncated", err)
+	}
+
+	// A malicious or buggy peer clears FlagMoreFragments before the
+	// length prefix from the first fragment is satisfied.
+	final := append([]byte{0}, []byte("lo")...)
+	if err := r.Add(final); err != ErrLengthMismatch {
+		t.Fatalf("final fragment: got %v, want ErrLengthMismatch", err)
+	}
+}
+
+func TestReassemblerAcceptsCompleteMessage(t *testing.T) {
+	var r Reassembler
+
+	first := append([]byte{byte(FlagLengthIncluded | FlagMoreFragments), 0, 0, 0, 5}, []byte("hel")...)
+	if err := r.Add(first); err != ErrTruncated {
+		t.Fatalf("first fragment: got %v, want ErrTruncated", err)
+	}
+
+	final := append([]byte{0}, []byte("lo")...)
+	if err := r.Add(final); err != nil {
+		t.Fatalf("final fragment: unexpected error: %v", err)
+	}
+	if string(r.Bytes()) != "hello" {
+		t.Fatalf("got %q, want %q", r.Bytes(), "hello")
+	}
+}
+
+func TestEncodeTypeDataPropagatesError(t *testing.T) {
+	oversized := make([]byte, 300)
+	if _, err := EncodeTypeData(1, 1, 13, oversized); err == nil {
+		t.Fatal("expected an error for a type-data field larger than radius.Attribute can hold")
+	}
+}
@@ -0,0 +1,82 @@
+package eap
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// reassemblerWire is the gob-friendly mirror of Reassembler's unexported
+// fields, used by MarshalBinary/UnmarshalBinary so a Reassembler can cross
+// a SessionStore like RedisSessionStore.
+type reassemblerWire struct {
+	Buf         []byte
+	WantLength  int
+	LengthKnown bool
+	Started     bool
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, letting a Reassembler
+// be gob-encoded by RedisSessionStore (or any other store serializing
+// State) despite having only unexported fields.
+func (r *Reassembler) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	wire := reassemblerWire{
+		Buf:         r.buf,
+		WantLength:  r.wantLength,
+		LengthKnown: r.lengthKnown,
+		Started:     r.started,
+	}
+	if err := gob.NewEncoder(&buf).Encode(wire); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (r *Reassembler) UnmarshalBinary(data []byte) error {
+	var wire reassemblerWire
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&wire); err != nil {
+		return err
+	}
+	r.buf = wire.Buf
+	r.wantLength = wire.WantLength
+	r.lengthKnown = wire.LengthKnown
+	r.started = wire.Started
+	return nil
+}
+
+// fragmenterWire is the gob-friendly mirror of Fragmenter's unexported
+// fields.
+type fragmenterWire struct {
+	Data        []byte
+	Offset      int
+	MaxFragment int
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, letting a Fragmenter
+// be gob-encoded by RedisSessionStore despite having only unexported
+// fields.
+func (f *Fragmenter) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	wire := fragmenterWire{
+		Data:        f.data,
+		Offset:      f.offset,
+		MaxFragment: f.maxFragment,
+	}
+	if err := gob.NewEncoder(&buf).Encode(wire); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (f *Fragmenter) UnmarshalBinary(data []byte) error {
+	var wire fragmenterWire
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&wire); err != nil {
+		return err
+	}
+	f.data = wire.Data
+	f.offset = wire.Offset
+	f.maxFragment = wire.MaxFragment
+	return nil
+}
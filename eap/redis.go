@@ -0,0 +1,75 @@
+package eap
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"time"
+)
+
+// RedisClient is the subset of *redis.Client (github.com/redis/go-redis/v9)
+// that RedisSessionStore needs, so callers aren't forced onto a specific
+// client version or import path.
+type RedisClient interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Del(ctx context.Context, key string) error
+}
+
+// RedisSessionStore is a SessionStore backed by Redis, for HA deployments
+// where Access-Challenge round trips for the same session may land on
+// different RADIUS server instances.
+type RedisSessionStore struct {
+	Client RedisClient
+	// TTL bounds how long Redis retains an idle session. Defaults to
+	// 30s, comfortably longer than a NAS's Access-Challenge retry
+	// window.
+	TTL time.Duration
+
+	// KeyPrefix namespaces session keys within a shared Redis instance.
+	KeyPrefix string
+}
+
+func (r *RedisSessionStore) ttl() time.Duration {
+	if r.TTL == 0 {
+		return 30 * time.Second
+	}
+	return r.TTL
+}
+
+func (r *RedisSessionStore) key(key string) string {
+	return r.KeyPrefix + key
+}
+
+// Load implements SessionStore.
+func (r *RedisSessionStore) Load(key string) (*State, bool, error) {
+	raw, err := r.Client.Get(context.Background(), r.key(key))
+	if err != nil {
+		return nil, false, nil
+	}
+	if len(raw) == 0 {
+		return nil, false, nil
+	}
+
+	var s State
+	if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&s); err != nil {
+		return nil, false, err
+	}
+	return &s, true, nil
+}
+
+// Save implements SessionStore.
+func (r *RedisSessionStore) Save(key string, s *State) error {
+	s.UpdatedAt = time.Now()
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s); err != nil {
+		return err
+	}
+	return r.Client.Set(context.Background(), r.key(key), buf.Bytes(), r.ttl())
+}
+
+// Delete implements SessionStore.
+func (r *RedisSessionStore) Delete(key string) error {
+	return r.Client.Del(context.Background(), r.key(key))
+}
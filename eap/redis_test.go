@@ -0,0 +1,85 @@
+package eap
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedisClient is an in-memory stand-in for a real Redis client, enough
+// to exercise RedisSessionStore's (de)serialization path.
+type fakeRedisClient struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newFakeRedisClient() *fakeRedisClient {
+	return &fakeRedisClient{data: make(map[string][]byte)}
+}
+
+func (f *fakeRedisClient) Get(ctx context.Context, key string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.data[key], nil
+}
+
+func (f *fakeRedisClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (f *fakeRedisClient) Del(ctx context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.data, key)
+	return nil
+}
+
+func TestRedisSessionStoreRoundTripsMidHandshakeState(t *testing.T) {
+	store := &RedisSessionStore{Client: newFakeRedisClient()}
+
+	reassembler := Reassembler{}
+	if err := reassembler.Add([]byte{byte(FlagLengthIncluded | FlagMoreFragments), 0, 0, 0, 10, 'h', 'e', 'l'}); err != nil && err != ErrTruncated {
+		t.Fatalf("priming reassembler: %v", err)
+	}
+
+	fragmenter := NewFragmenter([]byte("some pending outbound TLS record bytes"), 8)
+	fragmenter.Next() // advance offset so Fragmenter has partial progress to round-trip
+
+	want := &State{
+		Reassembler:   reassembler,
+		Fragmenter:    fragmenter,
+		HandshakeDone: false,
+	}
+
+	if err := store.Save("session-key", want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, ok, err := store.Load("session-key")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected session to be found")
+	}
+
+	if string(got.Reassembler.Bytes()) != string(want.Reassembler.Bytes()) {
+		t.Fatalf("Reassembler buffer mismatch: got %q, want %q", got.Reassembler.Bytes(), want.Reassembler.Bytes())
+	}
+	if got.Fragmenter == nil {
+		t.Fatal("expected Fragmenter to survive the round trip")
+	}
+	if got.Fragmenter.offset != want.Fragmenter.offset {
+		t.Fatalf("Fragmenter offset mismatch: got %d, want %d", got.Fragmenter.offset, want.Fragmenter.offset)
+	}
+	if got.Fragmenter.maxFragment != want.Fragmenter.maxFragment {
+		t.Fatalf("Fragmenter maxFragment mismatch: got %d, want %d", got.Fragmenter.maxFragment, want.Fragmenter.maxFragment)
+	}
+	if got.HandshakeDone {
+		t.Fatal("expected HandshakeDone to remain false")
+	}
+}
@@ -0,0 +1,103 @@
+package eap
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// State is the handshake state kept between Access-Challenge round trips
+// for a single EAP session, correlated by the RADIUS State attribute.
+type State struct {
+	// Reassembler buffers inbound fragments of the TLS message currently
+	// being received.
+	Reassembler Reassembler
+	// Fragmenter holds the outbound TLS message currently being sent, if
+	// any fragments of it are still pending.
+	Fragmenter *Fragmenter
+	// HandshakeDone is true once the TLS handshake has completed and the
+	// session is ready to carry an inner authentication method.
+	HandshakeDone bool
+	// UpdatedAt is refreshed every time the session is stored, so a
+	// SessionStore can expire abandoned handshakes.
+	UpdatedAt time.Time
+}
+
+// SessionStore persists in-progress EAP handshake State keyed by the
+// RADIUS State attribute, so a handler can be invoked statelessly across
+// Access-Challenge round trips. Implementations must be safe for
+// concurrent use.
+type SessionStore interface {
+	// Load returns the State previously saved under key, or ok == false
+	// if none exists (or it has expired).
+	Load(key string) (s *State, ok bool, err error)
+	// Save stores s under key, replacing any previous value.
+	Save(key string, s *State) error
+	// Delete removes the session, e.g. once the handshake and inner
+	// authentication are complete.
+	Delete(key string) error
+}
+
+// NewStateKey returns a fresh random key suitable for use as a RADIUS
+// State attribute value, correlating subsequent Access-Challenges with a
+// SessionStore entry.
+func NewStateKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// MemorySessionStore is a SessionStore backed by an in-process map. It is
+// suitable for a single-instance deployment; use RedisSessionStore (or a
+// similar implementation) when running multiple RADIUS servers behind a
+// shared client base so a handshake can resume on any instance.
+type MemorySessionStore struct {
+	// TTL bounds how long an idle session is kept before Load reports it
+	// as not found. Zero means sessions never expire on their own.
+	TTL time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*State
+}
+
+// NewMemorySessionStore returns a MemorySessionStore that expires idle
+// sessions after ttl (or never, if ttl is zero).
+func NewMemorySessionStore(ttl time.Duration) *MemorySessionStore {
+	return &MemorySessionStore{TTL: ttl, sessions: make(map[string]*State)}
+}
+
+// Load implements SessionStore.
+func (m *MemorySessionStore) Load(key string) (*State, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if m.TTL > 0 && time.Since(s.UpdatedAt) > m.TTL {
+		delete(m.sessions, key)
+		return nil, false, nil
+	}
+	return s, true, nil
+}
+
+// Save implements SessionStore.
+func (m *MemorySessionStore) Save(key string, s *State) error {
+	s.UpdatedAt = time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[key] = s
+	return nil
+}
+
+// Delete implements SessionStore.
+func (m *MemorySessionStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, key)
+	return nil
+}
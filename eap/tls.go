@@ -0,0 +1,148 @@
+package eap
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"sync"
+)
+
+// Conn presents the TLS record layer driven by an EAP-TLS/PEAP/TTLS
+// handshake as a tls.Conn-like object, so a Handler serving Access-Requests
+// can hand inner authentication off to ordinary net/tls-shaped
+// code once the handshake completes, instead of hand-rolling record
+// parsing per request.
+//
+// Conn runs the real *tls.Conn handshake against an in-memory net.Pipe.
+// Feed delivers TLS records reassembled from inbound RADIUS fragments to
+// that pipe; Drain collects the records the handshake wants to send, for
+// the caller to fragment back out across Access-Challenges.
+//
+// net.Pipe is unbuffered and fully synchronous: a Write blocks until a
+// matching Read occurs. Reading c.peer directly from Drain (even with a
+// short deadline) would race the handshake goroutine's blocked Write, so
+// a dedicated pump goroutine instead reads c.peer continuously into
+// outgoing, an internal buffer guarded by mu; Drain only ever touches
+// that buffer and therefore never blocks or drops a record that's
+// already been written.
+type Conn struct {
+	tlsConn *tls.Conn
+	peer    net.Conn // our end of the pipe; writes here are read by tlsConn
+
+	mu       sync.Mutex
+	outgoing bytes.Buffer
+
+	handshakeErr  error
+	handshakeDone chan struct{}
+}
+
+// NewServerConn starts a server-side TLS handshake (used by EAP-TLS/PEAP/
+// TTLS authenticators) against config. The handshake runs in the
+// background; use Feed and Drain to pump bytes to and from it.
+func NewServerConn(config *tls.Config) *Conn {
+	client, server := net.Pipe()
+	c := &Conn{
+		tlsConn:       tls.Server(server, config),
+		peer:          client,
+		handshakeDone: make(chan struct{}),
+	}
+	go c.runHandshake()
+	go c.pumpOutgoing()
+	return c
+}
+
+// NewClientConn starts a client-side TLS handshake against config. It is
+// used by test harnesses and by EAP supplicants embedding this package.
+func NewClientConn(config *tls.Config) *Conn {
+	client, server := net.Pipe()
+	c := &Conn{
+		tlsConn:       tls.Client(client, config),
+		peer:          server,
+		handshakeDone: make(chan struct{}),
+	}
+	go c.runHandshake()
+	go c.pumpOutgoing()
+	return c
+}
+
+func (c *Conn) runHandshake() {
+	err := c.tlsConn.Handshake()
+	c.mu.Lock()
+	c.handshakeErr = err
+	c.mu.Unlock()
+	close(c.handshakeDone)
+}
+
+// pumpOutgoing continuously reads whatever the handshake writes to its
+// end of the pipe into outgoing, so Drain never has to read the pipe
+// itself. It exits once the pipe is closed.
+func (c *Conn) pumpOutgoing() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := c.peer.Read(buf)
+		if n > 0 {
+			c.mu.Lock()
+			c.outgoing.Write(buf[:n])
+			c.mu.Unlock()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Feed delivers a reassembled TLS record (or records) received from the
+// peer over EAP to the handshake.
+func (c *Conn) Feed(record []byte) error {
+	_, err := c.peer.Write(record)
+	return err
+}
+
+// Drain returns whatever bytes the handshake has written since the last
+// Drain call. It never blocks: if the handshake hasn't produced anything
+// yet, it returns an empty slice.
+func (c *Conn) Drain() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := append([]byte(nil), c.outgoing.Bytes()...)
+	c.outgoing.Reset()
+	return out, nil
+}
+
+// HandshakeComplete reports whether the TLS handshake has finished,
+// successfully or not.
+func (c *Conn) HandshakeComplete() bool {
+	select {
+	case <-c.handshakeDone:
+		return true
+	default:
+		return false
+	}
+}
+
+// HandshakeError returns the handshake's result once HandshakeComplete is
+// true; it is nil if the handshake has not finished yet or succeeded.
+func (c *Conn) HandshakeError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.handshakeErr
+}
+
+// ConnectionState exposes the negotiated TLS connection state, valid once
+// HandshakeComplete returns true.
+func (c *Conn) ConnectionState() tls.ConnectionState {
+	return c.tlsConn.ConnectionState()
+}
+
+// Underlying returns the wrapped *tls.Conn, so inner authentication
+// methods (MSCHAPv2, GTC) can read/write the now-established TLS channel
+// directly, e.g. via tls.Conn.Read/Write for EAP-TTLS/PEAP tunneled
+// attributes.
+func (c *Conn) Underlying() *tls.Conn {
+	return c.tlsConn
+}
+
+// Close tears down the handshake pipe.
+func (c *Conn) Close() error {
+	return c.peer.Close()
+}
@@ -0,0 +1,432 @@
+package radius
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Upstream is a single RADIUS server that a ProxyHandler can forward
+// requests to.
+type Upstream struct {
+	Addr   string // "host:port" of the upstream server
+	Secret []byte // shared secret used between the proxy and this upstream
+
+	// Weight influences selection when Pool.Strategy is ProxyWeighted.
+	// A weight of 0 is treated as 1.
+	Weight int
+
+	mu      sync.Mutex
+	healthy bool
+}
+
+// ProxyStrategy selects how an UpstreamPool picks among its healthy
+// upstreams.
+type ProxyStrategy int
+
+const (
+	// ProxyRoundRobin cycles through healthy upstreams in order.
+	ProxyRoundRobin ProxyStrategy = iota
+	// ProxyWeighted picks upstreams in proportion to their Weight.
+	ProxyWeighted
+)
+
+// UpstreamPool holds the set of upstream servers a ProxyHandler forwards
+// to, along with RFC 5997 Status-Server health-checking.
+type UpstreamPool struct {
+	Upstreams []*Upstream
+	Strategy  ProxyStrategy
+
+	// HealthCheckInterval is how often Status-Server probes are sent to
+	// each upstream. Defaults to 30s if zero.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout bounds how long a Status-Server probe waits for
+	// a reply before the upstream is marked unhealthy. Defaults to 5s.
+	HealthCheckTimeout time.Duration
+
+	mu   sync.Mutex
+	next int
+}
+
+// Start begins periodic Status-Server health checks against every
+// upstream in the pool. It returns immediately; checks run until ctx is
+// canceled.
+func (p *UpstreamPool) Start(ctx context.Context) {
+	interval := p.HealthCheckInterval
+	if interval == 0 {
+		interval = 30 * time.Second
+	}
+	for _, u := range p.Upstreams {
+		u.healthy = true
+		go p.healthCheckLoop(ctx, u, interval)
+	}
+}
+
+func (p *UpstreamPool) healthCheckLoop(ctx context.Context, u *Upstream, interval time.Duration) {
+	timeout := p.HealthCheckTimeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			healthy := statusServerProbe(u, timeout) == nil
+			u.mu.Lock()
+			u.healthy = healthy
+			u.mu.Unlock()
+		}
+	}
+}
+
+// statusServerProbe sends an RFC 5997 Status-Server request to u, signed
+// with the Message-Authenticator the spec requires (section 3), and
+// reports an error unless the upstream answers with a well-formed
+// Status-Server reply.
+func statusServerProbe(u *Upstream, timeout time.Duration) error {
+	probe := &Packet{
+		Code:       CodeStatusServer,
+		Identifier: byte(randomByte()),
+		Secret:     u.Secret,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resp, err := forward(ctx, probe, u.Addr, u.Secret, 1, timeout, true)
+	if err != nil {
+		return err
+	}
+
+	switch resp.Code {
+	case CodeAccessAccept, CodeAccountingResponse:
+		return nil
+	default:
+		return fmt.Errorf("radius: unexpected Status-Server reply code %v", resp.Code)
+	}
+}
+
+// Next returns the next upstream to try according to p.Strategy, skipping
+// any upstream currently marked unhealthy. It returns an error if every
+// upstream is unhealthy.
+func (p *UpstreamPool) Next() (*Upstream, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch p.Strategy {
+	case ProxyWeighted:
+		return p.nextWeightedLocked()
+	default:
+		return p.nextRoundRobinLocked()
+	}
+}
+
+func (p *UpstreamPool) nextRoundRobinLocked() (*Upstream, error) {
+	n := len(p.Upstreams)
+	for i := 0; i < n; i++ {
+		u := p.Upstreams[p.next%n]
+		p.next++
+		if u.isHealthy() {
+			return u, nil
+		}
+	}
+	return nil, errors.New("radius: no healthy upstream available")
+}
+
+func (p *UpstreamPool) nextWeightedLocked() (*Upstream, error) {
+	total := 0
+	for _, u := range p.Upstreams {
+		if u.isHealthy() {
+			total += weightOf(u)
+		}
+	}
+	if total == 0 {
+		return nil, errors.New("radius: no healthy upstream available")
+	}
+
+	p.next = (p.next + 1) % total
+	pick := p.next
+	for _, u := range p.Upstreams {
+		if !u.isHealthy() {
+			continue
+		}
+		pick -= weightOf(u)
+		if pick < 0 {
+			return u, nil
+		}
+	}
+	return p.Upstreams[0], nil
+}
+
+func weightOf(u *Upstream) int {
+	if u.Weight <= 0 {
+		return 1
+	}
+	return u.Weight
+}
+
+func (u *Upstream) isHealthy() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.healthy
+}
+
+// ProxyHandler forwards incoming requests to an UpstreamPool, re-signing
+// the request for the upstream and the response for the original NAS, and
+// collapses duplicate retransmits from the NAS onto a single in-flight
+// upstream request (RFC 2865 section 2.5).
+type ProxyHandler struct {
+	Pool *UpstreamPool
+
+	// Timeout bounds how long to wait for an upstream reply before
+	// retransmitting. Defaults to 2s.
+	Timeout time.Duration
+	// Retries is how many times to retransmit to the upstream before
+	// giving up. Defaults to 3.
+	Retries int
+
+	mu       sync.Mutex
+	inFlight map[proxyKey]*proxyTransaction
+}
+
+type proxyKey struct {
+	src           string
+	identifier    byte
+	authenticator [16]byte
+}
+
+type proxyTransaction struct {
+	done chan struct{}
+	resp *Packet
+	err  error
+}
+
+// ServeRADIUS implements Handler by forwarding r to the next healthy
+// upstream in p.Pool and writing the (re-signed) reply back to w.
+func (p *ProxyHandler) ServeRADIUS(w ResponseWriter, r *Request) {
+	key := proxyKey{
+		src:           r.RemoteAddr.String(),
+		identifier:    r.Packet.Identifier,
+		authenticator: r.Packet.Authenticator,
+	}
+
+	tx, leader := p.joinTransaction(key)
+	if leader {
+		tx.resp, tx.err = p.forwardToUpstream(r.Packet)
+		close(tx.done)
+		p.mu.Lock()
+		delete(p.inFlight, key)
+		p.mu.Unlock()
+	} else {
+		<-tx.done
+	}
+
+	if tx.err != nil {
+		return
+	}
+
+	w.Write(tx.resp)
+}
+
+func (p *ProxyHandler) joinTransaction(key proxyKey) (*proxyTransaction, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inFlight == nil {
+		p.inFlight = make(map[proxyKey]*proxyTransaction)
+	}
+	if tx, ok := p.inFlight[key]; ok {
+		return tx, false
+	}
+	tx := &proxyTransaction{done: make(chan struct{})}
+	p.inFlight[key] = tx
+	return tx, true
+}
+
+func (p *ProxyHandler) forwardToUpstream(req *Packet) (*Packet, error) {
+	upstream, err := p.Pool.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	retries := p.Retries
+	if retries == 0 {
+		retries = 3
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Duration(retries+1))
+	defer cancel()
+
+	resp, err := forward(ctx, req, upstream.Addr, upstream.Secret, retries, timeout, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-sign the reply with the downstream secret/authenticator so the
+	// original NAS can validate it. A Message-Authenticator carried in
+	// the upstream's reply was HMAC'd with the upstream secret and the
+	// upstream's own Request Authenticator input; it must be recomputed
+	// for the downstream NAS rather than passed through unchanged.
+	downstreamAuthenticator := req.Authenticator
+	resp.Secret = req.Secret
+	resp.Identifier = req.Identifier
+	resp.Authenticator = downstreamAuthenticator
+
+	if _, ok := resp.Attributes.Lookup(TypeMessageAuthenticator); ok {
+		if err := resignMessageAuthenticator(resp, req.Secret, downstreamAuthenticator); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// resignMessageAuthenticator recomputes p's Message-Authenticator
+// attribute for secret and requestAuthenticator (RFC 2869 section 5.14):
+// the HMAC-MD5 is taken over the packet as it will be transmitted, with
+// the Message-Authenticator value zeroed and the Authenticator field set
+// to the Request Authenticator rather than the (circularly dependent)
+// Response Authenticator that ends up on the wire.
+func resignMessageAuthenticator(p *Packet, secret []byte, requestAuthenticator [16]byte) error {
+	p.Attributes[TypeMessageAuthenticator] = []Attribute{make(Attribute, md5.Size)}
+
+	raw, err := p.Encode()
+	if err != nil {
+		return err
+	}
+
+	signingBuf := make([]byte, len(raw))
+	copy(signingBuf, raw)
+	copy(signingBuf[4:20], requestAuthenticator[:])
+
+	_, valueLen, err := findAttribute(signingBuf, byte(TypeMessageAuthenticator))
+	if err != nil {
+		return err
+	}
+	if valueLen != md5.Size {
+		return errors.New("radius: invalid Message-Authenticator length")
+	}
+
+	mac := hmac.New(md5.New, secret)
+	mac.Write(signingBuf)
+	p.Attributes[TypeMessageAuthenticator] = []Attribute{Attribute(mac.Sum(nil))}
+	return nil
+}
+
+// forward sends req to addr using secret, rewriting its Identifier and
+// Request Authenticator with a fresh nonce, retransmitting up to retries
+// times until a reply arrives or timeout elapses on every attempt. When
+// signMessageAuthenticator is true, a Message-Authenticator attribute
+// (required by some upstreams, e.g. for Status-Server per RFC 5997
+// section 3) is computed and attached before sending.
+func forward(ctx context.Context, req *Packet, addr string, secret []byte, retries int, timeout time.Duration, signMessageAuthenticator bool) (*Packet, error) {
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	upstreamReq := *req
+	upstreamReq.Secret = secret
+	upstreamReq.Identifier = byte(randomByte())
+	if _, err := rand.Read(upstreamReq.Authenticator[:]); err != nil {
+		return nil, err
+	}
+
+	if signMessageAuthenticator {
+		// Clone the attribute map: it's shared with req via the shallow
+		// struct copy above, and must not be mutated in place.
+		cloned := make(Attributes, len(upstreamReq.Attributes))
+		for t, values := range upstreamReq.Attributes {
+			cloned[t] = append([]Attribute(nil), values...)
+		}
+		cloned[TypeMessageAuthenticator] = []Attribute{make(Attribute, md5.Size)}
+		upstreamReq.Attributes = cloned
+	}
+
+	encoded, err := upstreamReq.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	if signMessageAuthenticator {
+		valueOffset, valueLen, err := findAttribute(encoded, byte(TypeMessageAuthenticator))
+		if err != nil {
+			return nil, err
+		}
+		if valueLen != md5.Size {
+			return nil, errors.New("radius: invalid Message-Authenticator length")
+		}
+		mac := hmac.New(md5.New, secret)
+		mac.Write(encoded)
+		copy(encoded[valueOffset:valueOffset+valueLen], mac.Sum(nil))
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		if _, err := conn.Write(encoded); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(time.Now().Add(timeout))
+
+		resp, err := readMatchingReply(conn, secret, upstreamReq.Identifier)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return resp, nil
+	}
+	return nil, fmt.Errorf("radius: upstream %s did not respond after %d retries: %w", addr, retries, lastErr)
+}
+
+// readMatchingReply reads from conn, using its already-set deadline, until
+// it gets a reply whose Identifier matches wantIdentifier or a read error
+// (including the deadline elapsing) ends the attempt. Discarding
+// unparseable or mismatched datagrams without returning
+// keeps a late reply to an earlier retransmit (or an unrelated
+// transaction sharing the socket) from being accepted in place of the
+// reply to this attempt.
+func readMatchingReply(conn net.Conn, secret []byte, wantIdentifier byte) (*Packet, error) {
+	b := make([]byte, 4096)
+	for {
+		n, err := conn.Read(b)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := Parse(b[:n], secret)
+		if err != nil {
+			continue
+		}
+		if resp.Identifier != wantIdentifier {
+			continue
+		}
+		return resp, nil
+	}
+}
+
+func randomByte() byte {
+	var b [1]byte
+	rand.Read(b[:])
+	return b[0]
+}
@@ -0,0 +1,60 @@
+package radius
+
+import "testing"
+
+func TestUpstreamPoolRoundRobinSkipsUnhealthy(t *testing.T) {
+	a := &Upstream{Addr: "a", healthy: true}
+	b := &Upstream{Addr: "b", healthy: false}
+	c := &Upstream{Addr: "c", healthy: true}
+	pool := &UpstreamPool{Upstreams: []*Upstream{a, b, c}}
+
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		u, err := pool.Next()
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		seen[u.Addr]++
+	}
+
+	if seen["b"] != 0 {
+		t.Fatalf("unhealthy upstream b was selected %d times", seen["b"])
+	}
+	if seen["a"] == 0 || seen["c"] == 0 {
+		t.Fatalf("expected both healthy upstreams to be used, got %v", seen)
+	}
+}
+
+func TestUpstreamPoolWeightedSelectionProportion(t *testing.T) {
+	heavy := &Upstream{Addr: "heavy", healthy: true, Weight: 9}
+	light := &Upstream{Addr: "light", healthy: true, Weight: 1}
+	pool := &UpstreamPool{
+		Upstreams: []*Upstream{heavy, light},
+		Strategy:  ProxyWeighted,
+	}
+
+	counts := map[string]int{}
+	const n = 1000
+	for i := 0; i < n; i++ {
+		u, err := pool.Next()
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		counts[u.Addr]++
+	}
+
+	// Each full cycle of total weight (10) gives heavy 9 selections, so
+	// n = 1000 lands at 90%; the bounds leave some slack around that.
+	if ratio := float64(counts["heavy"]) / float64(n); ratio < 0.85 || ratio > 0.95 {
+		t.Fatalf("expected heavy upstream to take ~90%% of traffic, got %.2f (%v)", ratio, counts)
+	}
+}
+
+func TestUpstreamPoolAllUnhealthy(t *testing.T) {
+	a := &Upstream{Addr: "a", healthy: false}
+	pool := &UpstreamPool{Upstreams: []*Upstream{a}}
+
+	if _, err := pool.Next(); err == nil {
+		t.Fatal("expected error when every upstream is unhealthy")
+	}
+}
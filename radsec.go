@@ -0,0 +1,237 @@
+package radius
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/pion/dtls/v2"
+)
+
+// RadSecSecret is the fixed shared secret defined by RFC 6614 section 2.3
+// for use with Parse/Encode when the transport itself (TLS or DTLS) already
+// provides mutual authentication and confidentiality.
+var RadSecSecret = []byte("radsec")
+
+// RadSecPort is the IANA assigned port for RADIUS/TLS (RFC 6614).
+const RadSecPort = 2083
+
+// TLSConfigSource supplies the *tls.Config a RadSec listener should use
+// for an incoming connection, selected from the TLS ClientHello (e.g. its
+// SNI server name). It is wired into ListenAndServeTLS via
+// tls.Config.GetConfigForClient, letting SecretSource-style per-client
+// configuration extend to TLS policy (a distinct CA pool or cipher suite
+// set per NAS group, say). GetConfigForClient runs before the handshake
+// completes, so selection can't key on the peer certificate itself; once
+// the connection is established, tls.Conn.ConnectionState().
+// PeerCertificates is available to a handler that needs per-certificate
+// behavior.
+type TLSConfigSource func(hello *tls.ClientHelloInfo) (*tls.Config, error)
+
+// ListenAndServeTLS listens on the TCP network address and serves
+// RADIUS/TLS (RadSec, RFC 6614) connections until Shutdown is called. Each
+// accepted connection may carry multiple pipelined requests, framed by the
+// RADIUS packet's own Length field. Because the TLS channel already
+// authenticates the peer, Parse/Encode are called with RadSecSecret rather
+// than a value from s.SecretSource. If s.TLSConfigSource is set, it is
+// consulted per connection via config.GetConfigForClient; otherwise config
+// is used as-is.
+func (s *Server) ListenAndServeTLS(config *tls.Config) error {
+	addr, err := net.ResolveTCPAddr("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+
+	if s.TLSConfigSource != nil {
+		config = config.Clone()
+		config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			return s.TLSConfigSource(hello)
+		}
+	}
+
+	ln, err := tls.Listen("tcp", addr.String(), config)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	return s.serveStreamListener(ln)
+}
+
+// ListenAndServeDTLS listens on the UDP network address and serves
+// RADIUS/DTLS (RFC 7360) connections until Shutdown is called.
+func (s *Server) ListenAndServeDTLS(config *dtls.Config) error {
+	addr, err := net.ResolveUDPAddr("udp", s.Addr)
+	if err != nil {
+		return err
+	}
+	ln, err := dtls.Listen("udp", addr, config)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	return s.serveStreamListener(ln)
+}
+
+func (s *Server) serveStreamListener(ln net.Listener) error {
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	s.waitGroup = &sync.WaitGroup{}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			// Shutdown closes s.listener to unblock Accept; treat the
+			// resulting error as a normal stop rather than a failure.
+			select {
+			case <-s.getDoneChan():
+				return nil
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		s.waitGroup.Add(1)
+		go func(conn net.Conn) {
+			defer s.waitGroup.Done()
+			defer conn.Close()
+			s.serveSecureConn(conn)
+		}(conn)
+	}
+}
+
+// serveSecureConn reads framed RADIUS packets off conn and hands each to
+// s.Handler, until a read fails (including conn being closed), a packet
+// fails to parse, or Shutdown has been called (checked before each read).
+func (s *Server) serveSecureConn(conn net.Conn) {
+	for {
+		select {
+		case <-s.getDoneChan():
+			return
+		default:
+		}
+
+		body, err := readFramedPacket(conn)
+		if err != nil {
+			return
+		}
+
+		packet, err := Parse(body, RadSecSecret)
+		if err != nil {
+			return
+		}
+
+		response := streamResponseWriter{conn: conn}
+		request := Request{
+			LocalAddr:  conn.LocalAddr(),
+			RemoteAddr: conn.RemoteAddr(),
+			Packet:     packet,
+		}
+
+		s.Handler.ServeRADIUS(&response, &request)
+	}
+}
+
+type streamResponseWriter struct {
+	conn net.Conn
+}
+
+func (w *streamResponseWriter) Write(packet *Packet) error {
+	packet.Secret = RadSecSecret
+	encoded, err := packet.Encode()
+	if err != nil {
+		return err
+	}
+	_, err = w.conn.Write(encoded)
+	return err
+}
+
+// DialTLS opens a RADIUS/TLS (RadSec) client connection to addr.
+func DialTLS(ctx context.Context, addr string, config *tls.Config) (*SecureConn, error) {
+	dialer := &tls.Dialer{Config: config}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &SecureConn{conn: conn}, nil
+}
+
+// DialDTLS opens a RADIUS/DTLS client connection to addr.
+func DialDTLS(ctx context.Context, addr string, config *dtls.Config) (*SecureConn, error) {
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dtls.DialWithContext(ctx, "udp", raddr, config)
+	if err != nil {
+		return nil, err
+	}
+	return &SecureConn{conn: conn}, nil
+}
+
+// SecureConn is a client-side RADIUS/TLS or RADIUS/DTLS connection. Packets
+// sent and received over it use RadSecSecret instead of a shared secret,
+// per RFC 6614 section 2.3 and RFC 7360 section 3.
+type SecureConn struct {
+	conn net.Conn
+}
+
+// Close closes the underlying transport connection.
+func (c *SecureConn) Close() error {
+	return c.conn.Close()
+}
+
+// Exchange encodes req, sends it over the secure transport, and returns
+// the next packet read from the connection as the reply. The reply's
+// Identifier is not compared with req's.
+func (c *SecureConn) Exchange(ctx context.Context, req *Packet) (*Packet, error) {
+	req.Secret = RadSecSecret
+	encoded, err := req.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.conn.Write(encoded); err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetReadDeadline(deadline)
+	}
+
+	body, err := readFramedPacket(c.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(body, RadSecSecret)
+}
+
+// readFramedPacket reads one RADIUS packet from r, framed by nothing more
+// than the packet's own 2-byte Length field at offset 2 (RFC 6614 section
+// 2.3: RadSec adds no additional framing over the stream transport).
+func readFramedPacket(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint16(header[2:4])
+	if length < 4 {
+		return nil, errors.New("radius: invalid packet length")
+	}
+
+	body := make([]byte, length)
+	copy(body, header)
+	if _, err := io.ReadFull(r, body[4:]); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,45 @@
+package radius
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestReadFramedPacket(t *testing.T) {
+	t.Run("reads exactly one packet's worth of bytes", func(t *testing.T) {
+		packet := []byte{1, 42, 0, 8, 'a', 'b', 'c', 'd'}
+		trailing := []byte{9, 9, 9}
+		r := bytes.NewReader(append(append([]byte{}, packet...), trailing...))
+
+		body, err := readFramedPacket(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(body, packet) {
+			t.Fatalf("got %v, want %v", body, packet)
+		}
+
+		rest, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("reading trailing bytes: %v", err)
+		}
+		if !bytes.Equal(rest, trailing) {
+			t.Fatalf("expected next read to start at the following packet, got %v", rest)
+		}
+	})
+
+	t.Run("rejects a length shorter than the header", func(t *testing.T) {
+		r := bytes.NewReader([]byte{1, 1, 0, 2})
+		if _, err := readFramedPacket(r); err == nil {
+			t.Fatal("expected an error for a too-short length field")
+		}
+	})
+
+	t.Run("propagates a short read", func(t *testing.T) {
+		r := bytes.NewReader([]byte{1, 1, 0, 20, 'x'})
+		if _, err := readFramedPacket(r); err == nil {
+			t.Fatal("expected an error when the body is truncated")
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package radius
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a per-source-IP token bucket used by Server.PerClientLimit
+// to protect a busy authenticator from a single misbehaving or compromised
+// NAS.
+type RateLimiter struct {
+	// Rate is how many requests per second each client is allowed,
+	// sustained.
+	Rate float64
+	// Burst is the maximum number of requests a client can send in a
+	// single instant before being rate-limited. Defaults to 1 if zero.
+	Burst int
+	// IdleExpiry removes a client's bucket after it has been inactive
+	// for this long, bounding memory use. Defaults to 5 minutes.
+	IdleExpiry time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing rate requests/second per
+// client IP, with the given burst.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{Rate: rate, Burst: burst}
+}
+
+// Allow reports whether a request from addr should be accepted, consuming
+// one token from that client's bucket if so.
+func (r *RateLimiter) Allow(addr net.Addr) bool {
+	ip := addrIP(addr)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.buckets == nil {
+		r.buckets = make(map[string]*tokenBucket)
+	}
+
+	burst := r.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+
+	now := time.Now()
+	b, ok := r.buckets[ip]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst) - 1, lastSeen: now}
+		r.buckets[ip] = b
+		r.evictLocked(now)
+		return true
+	}
+
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	b.tokens += elapsed * r.Rate
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (r *RateLimiter) evictLocked(now time.Time) {
+	expiry := r.IdleExpiry
+	if expiry == 0 {
+		expiry = 5 * time.Minute
+	}
+	for ip, b := range r.buckets {
+		if now.Sub(b.lastSeen) > expiry {
+			delete(r.buckets, ip)
+		}
+	}
+}
+
+func addrIP(addr net.Addr) string {
+	if udp, ok := addr.(*net.UDPAddr); ok {
+		return udp.IP.String()
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// DuplicateCache detects retransmitted requests per RFC 5080 section
+// 2.2.2: a NAS that doesn't receive a reply in time resends the identical
+// request (same source, Identifier, and Request Authenticator), and the
+// server should resend its cached reply rather than reprocess it, since
+// reprocessing a non-idempotent request (e.g. Accounting-Request) twice
+// can double-count it downstream.
+type DuplicateCache struct {
+	// TTL bounds how long a reply is cached after it is produced.
+	// Defaults to 2 minutes if zero, comfortably longer than a NAS's
+	// retransmit window.
+	TTL time.Duration
+
+	mu      sync.Mutex
+	entries map[duplicateKey]*duplicateEntry
+}
+
+type duplicateKey struct {
+	src           string
+	identifier    byte
+	authenticator [16]byte
+}
+
+type duplicateEntry struct {
+	response *Packet
+	storedAt time.Time
+}
+
+// NewDuplicateCache returns a DuplicateCache whose entries expire after
+// ttl (or the 2 minute default, if ttl is zero).
+func NewDuplicateCache(ttl time.Duration) *DuplicateCache {
+	return &DuplicateCache{TTL: ttl}
+}
+
+func (c *DuplicateCache) ttl() time.Duration {
+	if c.TTL == 0 {
+		return 2 * time.Minute
+	}
+	return c.TTL
+}
+
+func (c *DuplicateCache) key(addr net.Addr, p *Packet) duplicateKey {
+	return duplicateKey{
+		src:           addr.String(),
+		identifier:    p.Identifier,
+		authenticator: p.Authenticator,
+	}
+}
+
+// Lookup returns the cached response for a previously seen request with
+// the same {src, identifier, authenticator}, if any and not yet expired.
+func (c *DuplicateCache) Lookup(addr net.Addr, p *Packet) (*Packet, bool) {
+	k := c.key(addr, p)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(e.storedAt) > c.ttl() {
+		delete(c.entries, k)
+		return nil, false
+	}
+	return e.response, true
+}
+
+// Store records response as the reply to request, so a later retransmit
+// of request can be answered from cache.
+func (c *DuplicateCache) Store(addr net.Addr, request, response *Packet) {
+	k := c.key(addr, request)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[duplicateKey]*duplicateEntry)
+	}
+	now := time.Now()
+	c.entries[k] = &duplicateEntry{response: response, storedAt: now}
+	c.evictLocked(now)
+}
+
+// evictLocked removes every entry older than ttl. It is called from Store
+// rather than only lazily from Lookup, so a key that is never retransmitted
+// (e.g. an attacker varying {identifier, authenticator} per request) still
+// gets reclaimed instead of growing the map without bound.
+func (c *DuplicateCache) evictLocked(now time.Time) {
+	ttl := c.ttl()
+	for k, e := range c.entries {
+		if now.Sub(e.storedAt) > ttl {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// cachingResponseWriter wraps a ResponseWriter so that whatever the
+// handler writes is also recorded in a DuplicateCache, keyed on the
+// request that produced it.
+type cachingResponseWriter struct {
+	ResponseWriter
+	cache *DuplicateCache
+	addr  net.Addr
+	req   *Packet
+}
+
+func (w *cachingResponseWriter) Write(packet *Packet) error {
+	w.cache.Store(w.addr, w.req, packet)
+	return w.ResponseWriter.Write(packet)
+}
@@ -0,0 +1,33 @@
+package radius
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDuplicateCacheEvictsUnretrievedEntries(t *testing.T) {
+	c := NewDuplicateCache(time.Millisecond)
+	addr := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1812}
+
+	for i := 0; i < 3; i++ {
+		req := &Packet{Identifier: byte(i)}
+		resp := &Packet{Identifier: byte(i)}
+		c.Store(addr, req, resp)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	// A key that is never looked up again must still be reclaimed: Store
+	// sweeps expired entries itself instead of relying solely on a future
+	// Lookup of the same key.
+	c.Store(addr, &Packet{Identifier: 99}, &Packet{Identifier: 99})
+
+	c.mu.Lock()
+	n := len(c.entries)
+	c.mu.Unlock()
+
+	if n != 1 {
+		t.Fatalf("expected stale entries to be evicted on Store, got %d entries remaining", n)
+	}
+}
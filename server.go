@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,9 +15,54 @@ type Server struct {
 	Addr         string       // TCP address to listen on, ":radius" if empty
 	Handler      Handler      // handler to invoke
 	SecretSource SecretSource // Secret source Store
-	doneChan     chan struct{}
-	mu           sync.Mutex
-	waitGroup    *sync.WaitGroup
+
+	// MaxConcurrentRequests bounds how many requests ServeRADIUS may be
+	// running at once. A datagram received while the limit is held is
+	// dropped rather than queued, since the NAS will retransmit. Zero
+	// means unbounded.
+	MaxConcurrentRequests int
+
+	// PerClientLimit, if set, rate-limits incoming requests per source
+	// IP. Requests exceeding the limit are dropped.
+	PerClientLimit *RateLimiter
+
+	// DuplicateCache, if set, detects retransmits (RFC 5080 section
+	// 2.2.2) keyed on {src, identifier, authenticator} and replays the
+	// cached response instead of invoking Handler again.
+	DuplicateCache *DuplicateCache
+
+	// Metrics, if set, is notified of accepted/rate-limited/duplicated
+	// requests and in-flight counts.
+	Metrics ServerMetrics
+
+	// TLSConfigSource, if set, selects the *tls.Config ListenAndServeTLS
+	// uses for each incoming connection based on its ClientHello. See
+	// TLSConfigSource's doc comment for what it can and can't key on.
+	TLSConfigSource TLSConfigSource
+
+	sem      chan struct{}
+	inFlight int32
+
+	doneChan  chan struct{}
+	listener  net.Listener
+	mu        sync.Mutex
+	waitGroup *sync.WaitGroup
+}
+
+// ServerMetrics receives counters from Server so they can be wired to
+// Prometheus or any other monitoring system. All methods must be safe for
+// concurrent use.
+type ServerMetrics interface {
+	// Accepted is called once a request is handed to Handler.
+	Accepted()
+	// RateLimited is called when PerClientLimit rejects a request.
+	RateLimited()
+	// Duplicated is called when DuplicateCache answers from cache
+	// instead of invoking Handler.
+	Duplicated()
+	// InFlight is called with the current number of requests being
+	// handled, each time that count changes.
+	InFlight(n int)
 }
 
 //var DefaultServe = func() {}
@@ -93,6 +139,10 @@ func (s *Server) ListenAndServe() error {
 	defer conn.Close()
 
 	s.waitGroup = &sync.WaitGroup{}
+	if s.MaxConcurrentRequests > 0 {
+		s.sem = make(chan struct{}, s.MaxConcurrentRequests)
+	}
+
 	for {
 		select {
 		case <-s.getDoneChan():
@@ -110,9 +160,28 @@ func (s *Server) ListenAndServe() error {
 			return err
 		}
 
+		if s.PerClientLimit != nil && !s.PerClientLimit.Allow(addr) {
+			if s.Metrics != nil {
+				s.Metrics.RateLimited()
+			}
+			continue
+		}
+
+		if s.sem != nil {
+			select {
+			case s.sem <- struct{}{}:
+			default:
+				// At MaxConcurrentRequests; drop it, the NAS will retransmit.
+				continue
+			}
+		}
+
 		s.waitGroup.Add(1)
 		go func(p []byte, remoteAddr net.Addr) {
 			defer s.waitGroup.Done()
+			if s.sem != nil {
+				defer func() { <-s.sem }()
+			}
 
 			secret, err := s.SecretSource(remoteAddr)
 			if err != nil {
@@ -133,6 +202,16 @@ func (s *Server) ListenAndServe() error {
 				addr: remoteAddr,
 			}
 
+			if s.DuplicateCache != nil {
+				if cached, ok := s.DuplicateCache.Lookup(remoteAddr, packet); ok {
+					if s.Metrics != nil {
+						s.Metrics.Duplicated()
+					}
+					response.Write(cached)
+					return
+				}
+			}
+
 			request := Request{
 				LocalAddr:  conn.LocalAddr(),
 				RemoteAddr: remoteAddr,
@@ -140,7 +219,21 @@ func (s *Server) ListenAndServe() error {
 				//ctx:        s.ctx,
 			}
 
-			s.Handler.ServeRADIUS(&response, &request)
+			if s.Metrics != nil {
+				s.Metrics.Accepted()
+				s.Metrics.InFlight(int(atomic.AddInt32(&s.inFlight, 1)))
+			}
+
+			rw := ResponseWriter(&response)
+			if s.DuplicateCache != nil {
+				rw = &cachingResponseWriter{ResponseWriter: rw, cache: s.DuplicateCache, addr: remoteAddr, req: packet}
+			}
+
+			s.Handler.ServeRADIUS(rw, &request)
+
+			if s.Metrics != nil {
+				s.Metrics.InFlight(int(atomic.AddInt32(&s.inFlight, -1)))
+			}
 		}(b[:n], addr)
 	}
 }
@@ -176,6 +269,9 @@ var shutdownPollInterval = 500 * time.Millisecond
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.mu.Lock()
 	s.closeDoneChanLocked()
+	if s.listener != nil {
+		s.listener.Close()
+	}
 	s.mu.Unlock()
 
 	waitChan := make(chan struct{}, 1)